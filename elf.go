@@ -0,0 +1,40 @@
+// +build linux
+
+package main
+
+import (
+	"os"
+	"strings"
+
+	"trace-files/elfdeps"
+	"trace-files/ptracer"
+)
+
+// addElfDeps walks every executable or library path already in files and
+// adds each dependency elfdeps can resolve for it, so that libraries
+// loaded via dlopen - or ones the traced process was about to load when
+// it was killed - are still reported. New paths are added with
+// accessElfDep, since they were never themselves observed being opened.
+func addElfDeps(files fileSet) {
+	ldPath := strings.Split(os.Getenv("LD_LIBRARY_PATH"), ":")
+
+	// Copy the paths to resolve up front: addElfDeps must not recurse
+	// into dependencies this call itself discovers while it's still
+	// ranging over files.
+	paths := make([]string, 0, len(files))
+	for p, mode := range files {
+		if mode == accessExec || strings.Contains(p, ".so") {
+			paths = append(paths, p)
+		}
+	}
+
+	for _, p := range paths {
+		deps, err := elfdeps.Resolve(p, ldPath)
+		if err != nil {
+			continue
+		}
+		for _, dep := range deps {
+			files.add(ptracer.FileAccess{Path: dep, Mode: accessElfDep})
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package elfdeps resolves the shared-library dependencies of an ELF
+// binary, the way the dynamic linker would, so that libraries loaded via
+// dlopen after the tracer's last /proc/PID/maps snapshot - or libraries a
+// traced process was about to load when it was killed - can still be
+// reported.
+package elfdeps
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultLibDirs are consulted after ldPath and /etc/ld.so.conf, mirroring
+// the dynamic linker's own fallback search path.
+var defaultLibDirs = []string{"/lib", "/usr/lib", "/lib64", "/usr/lib64"}
+
+// Resolve reads path's DT_NEEDED entries and PT_INTERP segment, and
+// resolves each named library against ldPath, /etc/ld.so.conf (and its
+// "include"d fragments), and defaultLibDirs, in that order. It returns the
+// absolute paths it could resolve; a name it can't find anywhere is
+// silently dropped, since that mirrors what the dynamic linker itself
+// would do (fail at load time, not at trace time).
+func Resolve(path string, ldPath []string) ([]string, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("elfdeps: opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var needed []string
+	if libs, err := f.DynString(elf.DT_NEEDED); err == nil {
+		needed = append(needed, libs...)
+	}
+	if interp, err := interpOf(f); err == nil && interp != "" {
+		needed = append(needed, interp)
+	}
+
+	dirs := append(append([]string{}, ldPath...), LdConfigDirs()...)
+	dirs = append(dirs, defaultLibDirs...)
+
+	var resolved []string
+	for _, name := range needed {
+		if r := resolveOne(name, dirs); r != "" {
+			resolved = append(resolved, r)
+		}
+	}
+	return resolved, nil
+}
+
+// resolveOne finds name on disk: as-is if it's already absolute, otherwise
+// by joining it to each of dirs in turn.
+func resolveOne(name string, dirs []string) string {
+	if filepath.IsAbs(name) {
+		if isRegularFile(name) {
+			return name
+		}
+		return ""
+	}
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, name)
+		if isRegularFile(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// interpOf reads the path named by f's PT_INTERP segment, if it has one.
+func interpOf(f *elf.File) (string, error) {
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		data := make([]byte, prog.Filesz)
+		if _, err := prog.ReadAt(data, 0); err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\x00"), nil
+	}
+	return "", nil
+}
+
+// LdConfigDirs returns the library directories named by /etc/ld.so.conf,
+// following any "include" directives it contains.
+func LdConfigDirs() []string {
+	dirs, _ := parseLdConf("/etc/ld.so.conf", make(map[string]bool))
+	return dirs
+}
+
+func parseLdConf(path string, seen map[string]bool) ([]string, error) {
+	if seen[path] {
+		return nil, nil
+	}
+	seen[path] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := cutPrefix(line, "include"); ok {
+			matches, _ := filepath.Glob(strings.TrimSpace(rest))
+			for _, m := range matches {
+				sub, err := parseLdConf(m, seen)
+				if err == nil {
+					dirs = append(dirs, sub...)
+				}
+			}
+			continue
+		}
+		dirs = append(dirs, line)
+	}
+	return dirs, nil
+}
+
+// cutPrefix reports whether line starts with the "include" directive
+// keyword, returning the remainder of the line when it does.
+func cutPrefix(line, keyword string) (string, bool) {
+	if !strings.HasPrefix(line, keyword) {
+		return "", false
+	}
+	rest := line[len(keyword):]
+	if rest != "" && !strings.HasPrefix(rest, " ") && !strings.HasPrefix(rest, "\t") {
+		return "", false
+	}
+	return rest, true
+}
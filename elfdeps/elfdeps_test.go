@@ -0,0 +1,88 @@
+package elfdeps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCutPrefix(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantRest string
+		wantOK   bool
+	}{
+		{line: "include /etc/ld.so.conf.d/*.conf", wantRest: " /etc/ld.so.conf.d/*.conf", wantOK: true},
+		{line: "include", wantRest: "", wantOK: true},
+		{line: "includefoo /etc/ld.so.conf.d/*.conf", wantOK: false},
+		{line: "/usr/local/lib", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			rest, ok := cutPrefix(tt.line, "include")
+			if ok != tt.wantOK {
+				t.Fatalf("cutPrefix(%q) ok = %v, want %v", tt.line, ok, tt.wantOK)
+			}
+			if ok && rest != tt.wantRest {
+				t.Errorf("cutPrefix(%q) rest = %q, want %q", tt.line, rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParseLdConf(t *testing.T) {
+	dir := t.TempDir()
+
+	fragDir := filepath.Join(dir, "conf.d")
+	if err := os.Mkdir(fragDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	frag := filepath.Join(fragDir, "extra.conf")
+	if err := os.WriteFile(frag, []byte("/opt/lib\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	main := filepath.Join(dir, "ld.so.conf")
+	contents := "# a comment\n\n/usr/local/lib\ninclude " + filepath.Join(fragDir, "*.conf") + "\n"
+	if err := os.WriteFile(main, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs, err := parseLdConf(main, make(map[string]bool))
+	if err != nil {
+		t.Fatalf("parseLdConf: unexpected error: %v", err)
+	}
+
+	want := []string{"/usr/local/lib", "/opt/lib"}
+	if len(dirs) != len(want) {
+		t.Fatalf("parseLdConf = %v, want %v", dirs, want)
+	}
+	for i, d := range want {
+		if dirs[i] != d {
+			t.Errorf("parseLdConf[%d] = %q, want %q", i, dirs[i], d)
+		}
+	}
+}
+
+func TestParseLdConfCyclicInclude(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ld.so.conf")
+	if err := os.WriteFile(path, []byte("/lib\ninclude "+path+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs, err := parseLdConf(path, make(map[string]bool))
+	if err != nil {
+		t.Fatalf("parseLdConf: unexpected error: %v", err)
+	}
+	if len(dirs) != 1 || dirs[0] != "/lib" {
+		t.Errorf("parseLdConf with self-include = %v, want [/lib]", dirs)
+	}
+}
+
+func TestParseLdConfMissingFile(t *testing.T) {
+	if _, err := parseLdConf("/nonexistent/ld.so.conf", make(map[string]bool)); err == nil {
+		t.Fatal("parseLdConf: expected error for a missing file")
+	}
+}
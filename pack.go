@@ -0,0 +1,114 @@
+// +build linux
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// skipPrefixes are paths pack never archives by default, since they're
+// pseudo filesystems rather than real reproducibility inputs.
+var skipPrefixes = []string{"/proc", "/sys", "/dev"}
+
+// shouldSkip reports whether path is one pack should leave out of the
+// archive by default: pseudo filesystems, and anything under
+// $HOME/.cache.
+func shouldSkip(path string) bool {
+	for _, prefix := range skipPrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		if strings.HasPrefix(path, filepath.Join(home, ".cache")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// packCmd implements the `trace-files pack <outdir> <trace.json>`
+// subcommand: it reads a `--format json` trace and tars every file it
+// references, preserving absolute paths, into a `.rpz`-style archive under
+// outdir.
+func packCmd(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: trace-files pack <outdir> <trace.json>")
+	}
+	outdir, traceFile := args[0], args[1]
+
+	data, err := os.ReadFile(traceFile)
+	if err != nil {
+		return fmt.Errorf("error reading trace: %w", err)
+	}
+	var rep jsonReport
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return fmt.Errorf("error parsing trace: %w", err)
+	}
+
+	if err := os.MkdirAll(outdir, 0o755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(traceFile), filepath.Ext(traceFile)) + ".rpz"
+	archivePath := filepath.Join(outdir, name)
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("error creating archive: %w", err)
+	}
+	defer out.Close()
+
+	gw := gzip.NewWriter(out)
+	defer gw.Close()
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+
+	for _, f := range rep.Files {
+		if shouldSkip(f.Path) {
+			continue
+		}
+		if err := addFileToArchive(tw, f.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping %s: %s\n", f.Path, err)
+		}
+	}
+
+	fmt.Printf("wrote %s\n", archivePath)
+	return nil
+}
+
+// addFileToArchive adds the regular file at path to tw, preserving its
+// absolute path as the archive member name.
+func addFileToArchive(tw *tar.Writer, path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = strings.TrimPrefix(path, "/")
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,82 @@
+package procmaps
+
+import "testing"
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		line         string
+		wantPathname string
+		wantExec     bool
+		wantAnon     bool
+		wantSet      bool
+	}{
+		{
+			name:         "executable binary mapping",
+			line:         "00400000-00452000 r-xp 00000000 08:02 173521      /usr/bin/dbus-daemon",
+			wantPathname: "/usr/bin/dbus-daemon",
+			wantExec:     true,
+			wantAnon:     false,
+			wantSet:      true,
+		},
+		{
+			name:         "anonymous heap mapping",
+			line:         "00e03000-00e24000 rw-p 00000000 00:00 0           [heap]",
+			wantPathname: "[heap]",
+			wantExec:     false,
+			wantAnon:     true,
+			wantSet:      false,
+		},
+		{
+			name:         "stack pseudo-path, non-anonymous inode",
+			line:         "7ffee1234000-7ffee1255000 rw-p 00000000 00:00 1234        [stack]",
+			wantPathname: "[stack]",
+			wantExec:     false,
+			wantAnon:     false,
+			wantSet:      false,
+		},
+		{
+			name:         "deleted file",
+			line:         "7f0a12345000-7f0a12346000 r--p 00000000 08:02 98765       /tmp/libfoo.so.1 (deleted)",
+			wantPathname: "/tmp/libfoo.so.1 (deleted)",
+			wantExec:     false,
+			wantAnon:     false,
+			wantSet:      false,
+		},
+		{
+			name:         "no pathname field at all",
+			line:         "7f0a12345000-7f0a12346000 rw-p 00000000 00:00 0",
+			wantPathname: "",
+			wantExec:     false,
+			wantAnon:     true,
+			wantSet:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseLine(tt.line)
+			if err != nil {
+				t.Fatalf("ParseLine(%q): unexpected error: %v", tt.line, err)
+			}
+			if m.Pathname != tt.wantPathname {
+				t.Errorf("Pathname = %q, want %q", m.Pathname, tt.wantPathname)
+			}
+			if got := m.Executable(); got != tt.wantExec {
+				t.Errorf("Executable() = %v, want %v", got, tt.wantExec)
+			}
+			if got := m.Anonymous(); got != tt.wantAnon {
+				t.Errorf("Anonymous() = %v, want %v", got, tt.wantAnon)
+			}
+			if got := m.PathnameSet(); got != tt.wantSet {
+				t.Errorf("PathnameSet() = %v, want %v", got, tt.wantSet)
+			}
+		})
+	}
+}
+
+func TestParseLineMalformed(t *testing.T) {
+	if _, err := ParseLine("not a maps line"); err == nil {
+		t.Fatal("ParseLine: expected error for malformed line, got nil")
+	}
+}
@@ -0,0 +1,386 @@
+// Package ptracer implements the architecture-independent half of
+// trace-files' ptrace loop: reading path arguments out of a tracee's
+// registers, tracking per-tid state across forks, and dispatching
+// observed file accesses to a caller-supplied set of hooks. Both the CLI
+// in package main and the server package build on top of it.
+
+// +build linux
+
+package ptracer
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"trace-files/procmaps"
+	"trace-files/syscalls"
+
+	"golang.org/x/sys/unix"
+)
+
+// AccessMode describes how a tracee touched a path.
+type AccessMode int
+
+const (
+	AccessRead AccessMode = iota
+	AccessWrite
+	AccessExec
+	// AccessElfDep marks a path that was never itself traced, but was
+	// pulled in by elfdeps as a DT_NEEDED or PT_INTERP dependency of a
+	// traced binary or library.
+	AccessElfDep
+)
+
+func (m AccessMode) String() string {
+	switch m {
+	case AccessWrite:
+		return "write"
+	case AccessExec:
+		return "exec"
+	case AccessElfDep:
+		return "elf-dep"
+	default:
+		return "read"
+	}
+}
+
+// FileAccess records a single path observed at a syscall boundary.
+type FileAccess struct {
+	Path string
+	Mode AccessMode
+}
+
+// Tracee holds the per-tid state the loop needs to make sense of the
+// interleaved syscall-enter/syscall-exit stops it receives once a traced
+// program has more than one thread or process.
+type Tracee struct {
+	InSyscall   bool
+	Cwd         string
+	FilesOpened map[int]string // fd -> path
+}
+
+func newTracee(cwd string) *Tracee {
+	return &Tracee{Cwd: cwd, FilesOpened: make(map[int]string)}
+}
+
+// syscallNr, syscallRet, and syscallArg decode a stopped tracee's syscall
+// number, return value, and n'th argument (0-indexed) out of its
+// registers. Each architecture lays these out differently - and gives
+// unix.PtraceRegs itself a different shape - so they're implemented per
+// GOARCH in regs_amd64.go, regs_arm64.go, and regs_386.go.
+
+// readCString reads a NUL-terminated string from the tracee's memory at
+// addr, one word at a time.
+func readCString(pid int, addr uintptr) (string, error) {
+	var sb strings.Builder
+	buf := make([]byte, unix.SizeofPtr)
+	for {
+		n, err := unix.PtracePeekData(pid, addr, buf)
+		if err != nil {
+			return "", fmt.Errorf("PtracePeekData at %#x: %w", addr, err)
+		}
+		for i := 0; i < n; i++ {
+			if buf[i] == 0 {
+				return sb.String(), nil
+			}
+			sb.WriteByte(buf[i])
+		}
+		addr += uintptr(n)
+	}
+}
+
+// dirOf resolves the directory a dirfd argument refers to, following the
+// `*at` syscall convention: AT_FDCWD means "the tracee's current working
+// directory", anything else is resolved through /proc/PID/fd/<dirfd>.
+func dirOf(pid int, dirfd int32, cwd string) string {
+	if dirfd == unix.AT_FDCWD {
+		return cwd
+	}
+	link, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", pid, dirfd))
+	if err != nil {
+		return cwd
+	}
+	return link
+}
+
+// resolveFd turns a bare fd argument (such as fchdir's) into the path it
+// refers to: the path this tracee is already known to have opened it
+// under, if any, otherwise a fresh /proc/PID/fd readlink.
+func resolveFd(pid int, fd int32, cwd string, opened map[int]string) string {
+	if path, ok := opened[int(fd)]; ok {
+		return path
+	}
+	return dirOf(pid, fd, cwd)
+}
+
+// resolvePath turns a (possibly relative) path captured from a tracee into
+// an absolute one, given the directory it should be resolved against.
+func resolvePath(dir, path string) string {
+	if strings.HasPrefix(path, "/") {
+		return path
+	}
+	return dir + "/" + path
+}
+
+// InitialCwd reads a tracee's working directory at attach time.
+func InitialCwd(pid int) string {
+	cwd, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid))
+	if err != nil {
+		return ""
+	}
+	return cwd
+}
+
+func modeOf(m syscalls.Mode) AccessMode {
+	switch m {
+	case syscalls.ModeWrite:
+		return AccessWrite
+	case syscalls.ModeExec:
+		return AccessExec
+	default:
+		return AccessRead
+	}
+}
+
+// openAccessMode classifies an open/openat access from its flags
+// argument: syscalls.Info.Mode alone can't tell read from write apart
+// for these, since every open variant is tabled as ModeRead regardless
+// of the O_WRONLY/O_RDWR/O_CREAT bits the caller actually passed.
+func openAccessMode(flags uint64) AccessMode {
+	switch flags & unix.O_ACCMODE {
+	case unix.O_WRONLY, unix.O_RDWR:
+		return AccessWrite
+	}
+	if flags&(unix.O_CREAT|unix.O_TRUNC|unix.O_APPEND) != 0 {
+		return AccessWrite
+	}
+	return AccessRead
+}
+
+// captureSyscallExit inspects a tracee that has just returned from the
+// syscall described by info and, if info names a path argument, returns
+// the file access it performed. ok is false when the return value in RAX
+// indicated an error, or the path could not be read.
+func captureSyscallExit(pid int, regs *unix.PtraceRegs, cwd string, info syscalls.Info) (fa FileAccess, ok bool) {
+	pathArg := info.PathArg()
+	if pathArg < 0 {
+		return FileAccess{}, false
+	}
+	// A negative return value means the syscall failed; don't report
+	// paths for failed attempts.
+	if syscallRet(regs) < 0 {
+		return FileAccess{}, false
+	}
+
+	dir := cwd
+	if dirfdArg := info.DirFDArg(); dirfdArg >= 0 {
+		dir = dirOf(pid, int32(syscallArg(regs, dirfdArg)), cwd)
+	}
+
+	raw, err := readCString(pid, uintptr(syscallArg(regs, pathArg)))
+	if err != nil {
+		return FileAccess{}, false
+	}
+	if raw == "" {
+		// glibc compiles some fstat-style calls (fstat(fd) ->
+		// newfstatat(fd, "", AT_EMPTY_PATH)) down to an empty path that
+		// names the fd itself, not a real path relative to dir;
+		// resolvePath would otherwise turn this into a bogus "dir/"
+		// entry in the file set.
+		return FileAccess{}, false
+	}
+
+	mode := modeOf(info.Mode)
+	if flagsArg := info.FlagsArg(); flagsArg >= 0 {
+		mode = openAccessMode(syscallArg(regs, flagsArg))
+	}
+
+	return FileAccess{Path: resolvePath(dir, raw), Mode: mode}, true
+}
+
+// MappedLibraries snapshots /proc/PID/maps right after an execve, so that
+// the interpreter and shared libraries the dynamic linker loads before the
+// next syscall-stop are still reported. Pseudo-paths like "[heap]" and
+// anonymous mappings are discarded, since they carry no file to report.
+func MappedLibraries(pid int) []FileAccess {
+	maps, err := procmaps.ReadProcMaps(pid)
+	if err != nil {
+		return nil
+	}
+
+	var out []FileAccess
+	for _, m := range maps {
+		if m.Anonymous() || !m.PathnameSet() {
+			continue
+		}
+		mode := AccessRead
+		if m.Executable() {
+			mode = AccessExec
+		}
+		out = append(out, FileAccess{Path: m.Pathname, Mode: mode})
+	}
+	return out
+}
+
+// syscallStopSignal is the signal number the kernel reports for a
+// syscall-stop once PTRACE_O_TRACESYSGOOD is set: ordinary SIGTRAP with its
+// high bit set, distinguishing it from a plain signal-stop or an
+// event-stop (fork/vfork/clone/exit), both of which also arrive as SIGTRAP.
+const syscallStopSignal = unix.SIGTRAP | 0x80
+
+// Options are the ptrace options a caller must set on pid (via
+// unix.PtraceSetOptions) before calling Loop.
+const Options = unix.PTRACE_O_TRACEVFORK | unix.PTRACE_O_TRACEFORK | unix.PTRACE_O_TRACECLONE |
+	unix.PTRACE_O_TRACESYSGOOD | unix.PTRACE_O_TRACEEXIT
+
+// Hooks are called as Loop observes events in the traced process tree.
+// Any of them may be left nil.
+type Hooks struct {
+	// OnFileAccess is called once per path-bearing syscall that
+	// completed successfully.
+	OnFileAccess func(tid int, fa FileAccess)
+	// OnFork is called when a tracee forks, vforks, or clones, with the
+	// parent and (now also traced) child tids.
+	OnFork func(parent, child int)
+	// OnExit is called when a tracee exits, with its exit status: the code
+	// it called _exit with, or 128+signal if it was killed by a signal,
+	// matching the shell's convention for reporting a signal death.
+	OnExit func(tid int, code int)
+}
+
+// Loop drives the ptrace event loop for the whole process tree rooted at
+// pid, which must already be stopped at its initial exec trap with
+// Options set via unix.PtraceSetOptions, until every tracee has exited.
+//
+// Because wait4(-1, ...) can report a stop from any tracee in the calling
+// thread's process, Loop dispatches on whichever tid it actually gets back,
+// keyed through a per-tid Tracee, rather than assuming stops arrive for a
+// single fixed pid. Each tracee is resumed with PtraceSyscall right where
+// its own stop was handled, never from a shared variable re-read at the
+// top of the loop - a tid that just reported Exited/Signaled is gone and
+// must never be resumed at all, on pain of PtraceSyscall failing with
+// ESRCH the moment any non-root tracee exits before the others.
+func Loop(pid int, hooks Hooks) error {
+	tracees := map[int]*Tracee{pid: newTracee(InitialCwd(pid))}
+
+	if err := unix.PtraceSyscall(pid, 0); err != nil {
+		return fmt.Errorf("error calling ptrace syscall: %w", err)
+	}
+
+	for {
+		var status unix.WaitStatus
+		tid, err := unix.Wait4(-1, &status, 0, nil)
+		if err != nil {
+			return fmt.Errorf("error calling wait4: %w", err)
+		}
+
+		if status.Exited() || status.Signaled() {
+			delete(tracees, tid)
+			if hooks.OnExit != nil {
+				code := status.ExitStatus()
+				if status.Signaled() {
+					code = 128 + int(status.Signal())
+				}
+				hooks.OnExit(tid, code)
+			}
+			if len(tracees) == 0 {
+				break
+			}
+			// tid has already been reaped; there is nothing left to
+			// resume it into.
+			continue
+		}
+
+		tc, ok := tracees[tid]
+		if !ok {
+			tc = newTracee(InitialCwd(tid))
+			tracees[tid] = tc
+		}
+
+		if !status.Stopped() {
+			if err := unix.PtraceSyscall(tid, 0); err != nil {
+				return fmt.Errorf("error calling ptrace syscall: %w", err)
+			}
+			continue
+		}
+
+		resumeSig := 0
+		switch sig := status.StopSignal(); {
+		case sig == syscallStopSignal:
+			tc.InSyscall = !tc.InSyscall
+			if !tc.InSyscall {
+				var regs unix.PtraceRegs
+				if err := unix.PtraceGetRegs(tid, &regs); err == nil {
+					nr := syscallNr(&regs)
+					if fchdirNr, ok := syscalls.FchdirNr(runtime.GOARCH); ok && nr == fchdirNr {
+						if syscallRet(&regs) >= 0 {
+							tc.Cwd = resolveFd(tid, int32(syscallArg(&regs, 0)), tc.Cwd, tc.FilesOpened)
+						}
+					} else if info, known := syscalls.Lookup(runtime.GOARCH, nr); known {
+						switch info.Name {
+						case "execve", "execveat":
+							// A successful execve clobbers the argument
+							// registers captureSyscallExit would read, so
+							// it can never report this path itself; the
+							// exec'd binary and whatever ld.so pulled in
+							// for it are only visible in the fresh
+							// /proc/PID/maps snapshot below.
+							for _, mapped := range MappedLibraries(tid) {
+								if hooks.OnFileAccess != nil {
+									hooks.OnFileAccess(tid, mapped)
+								}
+							}
+						default:
+							if fa, ok := captureSyscallExit(tid, &regs, tc.Cwd, info); ok {
+								if hooks.OnFileAccess != nil {
+									hooks.OnFileAccess(tid, fa)
+								}
+								switch info.Name {
+								case "chdir":
+									tc.Cwd = fa.Path
+								case "open", "openat":
+									if fd := syscallRet(&regs); fd >= 0 {
+										tc.FilesOpened[int(fd)] = fa.Path
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		case sig == unix.SIGTRAP && status.TrapCause() != 0:
+			switch status.TrapCause() {
+			case unix.PTRACE_EVENT_FORK, unix.PTRACE_EVENT_VFORK, unix.PTRACE_EVENT_CLONE:
+				childTid, err := unix.PtraceGetEventMsg(tid)
+				if err == nil {
+					if _, seeded := tracees[int(childTid)]; !seeded {
+						tracees[int(childTid)] = newTracee(tc.Cwd)
+					}
+					if hooks.OnFork != nil {
+						hooks.OnFork(tid, int(childTid))
+					}
+				}
+			}
+			// PTRACE_EVENT_EXIT and others: nothing to capture.
+		default:
+			// An ordinary signal-delivery-stop: the tracee was about to
+			// receive sig when ptrace intercepted it. Re-inject it on
+			// resume (SIGSTOP itself is the exception - it's what
+			// produced the group-stop, not something the tracee is
+			// still owed), or it's silently swallowed and anything
+			// waiting on it - e.g. a parent blocked on SIGCHLD in
+			// wait() - hangs forever.
+			if sig != unix.SIGSTOP {
+				resumeSig = int(sig)
+			}
+		}
+
+		if err := unix.PtraceSyscall(tid, resumeSig); err != nil {
+			return fmt.Errorf("error calling ptrace syscall: %w", err)
+		}
+	}
+
+	return nil
+}
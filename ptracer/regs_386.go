@@ -0,0 +1,36 @@
+package ptracer
+
+import "golang.org/x/sys/unix"
+
+// syscallNr returns the syscall number a tracee is stopped in, and
+// syscallArg its n'th argument (0-indexed), per the ia32 kernel calling
+// convention: eax at entry (preserved in orig_eax across the syscall),
+// then ebx, ecx, edx, esi, edi, ebp. PtraceRegs' fields are int32 here, so
+// each is widened through uint32 first to avoid sign-extending a negative
+// register value into a huge uint64.
+func syscallNr(regs *unix.PtraceRegs) uint64 {
+	return uint64(uint32(regs.Orig_eax))
+}
+
+func syscallRet(regs *unix.PtraceRegs) int64 {
+	return int64(regs.Eax)
+}
+
+func syscallArg(regs *unix.PtraceRegs, n int) uint64 {
+	switch n {
+	case 0:
+		return uint64(uint32(regs.Ebx))
+	case 1:
+		return uint64(uint32(regs.Ecx))
+	case 2:
+		return uint64(uint32(regs.Edx))
+	case 3:
+		return uint64(uint32(regs.Esi))
+	case 4:
+		return uint64(uint32(regs.Edi))
+	case 5:
+		return uint64(uint32(regs.Ebp))
+	default:
+		panic("ptracer: invalid argument index")
+	}
+}
@@ -0,0 +1,34 @@
+package ptracer
+
+import "golang.org/x/sys/unix"
+
+// syscallNr returns the syscall number a tracee is stopped in, and
+// syscallArg its n'th argument (0-indexed), per the amd64 System V kernel
+// calling convention: rax at entry (preserved in orig_rax across the
+// syscall), then rdi, rsi, rdx, r10, r8, r9.
+func syscallNr(regs *unix.PtraceRegs) uint64 {
+	return regs.Orig_rax
+}
+
+func syscallRet(regs *unix.PtraceRegs) int64 {
+	return int64(regs.Rax)
+}
+
+func syscallArg(regs *unix.PtraceRegs, n int) uint64 {
+	switch n {
+	case 0:
+		return regs.Rdi
+	case 1:
+		return regs.Rsi
+	case 2:
+		return regs.Rdx
+	case 3:
+		return regs.R10
+	case 4:
+		return regs.R8
+	case 5:
+		return regs.R9
+	default:
+		panic("ptracer: invalid argument index")
+	}
+}
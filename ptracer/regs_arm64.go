@@ -0,0 +1,22 @@
+package ptracer
+
+import "golang.org/x/sys/unix"
+
+// syscallNr returns the syscall number a tracee is stopped in, and
+// syscallArg its n'th argument (0-indexed), per the arm64 calling
+// convention: x8 holds the syscall number, x0-x5 the first six arguments,
+// and the return value comes back in x0.
+func syscallNr(regs *unix.PtraceRegs) uint64 {
+	return regs.Regs[8]
+}
+
+func syscallRet(regs *unix.PtraceRegs) int64 {
+	return int64(regs.Regs[0])
+}
+
+func syscallArg(regs *unix.PtraceRegs, n int) uint64 {
+	if n < 0 || n > 5 {
+		panic("ptracer: invalid argument index")
+	}
+	return regs.Regs[n]
+}
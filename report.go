@@ -0,0 +1,129 @@
+// +build linux
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// outputFormat selects how a completed trace is rendered.
+type outputFormat string
+
+const (
+	formatText     outputFormat = "text"
+	formatJSON     outputFormat = "json"
+	formatReprozip outputFormat = "reprozip"
+)
+
+// traceResult is everything a report needs to render a finished trace in
+// any of the supported formats.
+type traceResult struct {
+	Argv    []string
+	Environ []string
+	Workdir string
+	Files   fileSet
+}
+
+// writeReport renders result in the given format to w.
+func writeReport(w io.Writer, format outputFormat, result traceResult) error {
+	switch format {
+	case formatJSON:
+		return writeJSONReport(w, result)
+	case formatReprozip:
+		return writeReprozipReport(w, result)
+	default:
+		return writeTextReport(w, result)
+	}
+}
+
+// writeTextReport reproduces trace-files' original output, plus the access
+// mode that procmaps/syscalls now let us tell apart.
+func writeTextReport(w io.Writer, result traceResult) error {
+	for i, p := range sortedPaths(result.Files) {
+		if _, err := fmt.Fprintf(w, "%d\t%s\t%s\n", i, result.Files[p], p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFileRecord is one entry in a jsonReport's Files list.
+type jsonFileRecord struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+}
+
+// jsonReport is the `--format json` document, and also what `pack` reads
+// back in.
+type jsonReport struct {
+	Argv  []string         `json:"argv"`
+	Files []jsonFileRecord `json:"files"`
+}
+
+func writeJSONReport(w io.Writer, result traceResult) error {
+	rep := jsonReport{Argv: result.Argv}
+	for _, p := range sortedPaths(result.Files) {
+		rep.Files = append(rep.Files, jsonFileRecord{Path: p, Mode: result.Files[p].String()})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// fileRole classifies a path for the reprozip report, mirroring the
+// binary/library/data/other split ReproZip itself uses.
+func fileRole(path string, mode accessMode) string {
+	switch {
+	case mode == accessExec:
+		return "binary"
+	case strings.Contains(path, "/lib/") || strings.Contains(path, "/lib64/") || strings.Contains(path, ".so"):
+		return "library"
+	case strings.HasPrefix(path, "/proc/") || strings.HasPrefix(path, "/sys/") || strings.HasPrefix(path, "/dev/"):
+		return "other"
+	default:
+		return "data"
+	}
+}
+
+// writeReprozipReport writes a config.yml close enough to ReproZip's own
+// schema that `reprounzip` can make sense of it: the traced command, and
+// the files it touched, split into ReproZip's binary/library/data/other
+// roles.
+func writeReprozipReport(w io.Writer, result traceResult) error {
+	fmt.Fprintf(w, "version: '0.7'\n")
+	fmt.Fprintf(w, "runs:\n")
+	fmt.Fprintf(w, "  - argv: %s\n", yamlList(result.Argv))
+	fmt.Fprintf(w, "    environ: %s\n", yamlList(result.Environ))
+	fmt.Fprintf(w, "    workingdir: %q\n", result.Workdir)
+	fmt.Fprintf(w, "inputs_outputs:\n")
+	for _, p := range sortedPaths(result.Files) {
+		mode := result.Files[p]
+		fmt.Fprintf(w, "  - path: %q\n", p)
+		fmt.Fprintf(w, "    role: %s\n", fileRole(p, mode))
+		fmt.Fprintf(w, "    mode: %s\n", mode)
+	}
+	return nil
+}
+
+// yamlList renders items as a YAML flow sequence of double-quoted scalars.
+func yamlList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, it := range items {
+		quoted[i] = fmt.Sprintf("%q", it)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// sortedPaths returns files' paths sorted, so every report is deterministic.
+func sortedPaths(files fileSet) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,61 @@
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"trace-files/server"
+)
+
+// serveCmd implements `trace-files serve <socket-path>`: it runs the RPC
+// server from package server until the socket is removed or the process
+// is killed.
+func serveCmd(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: trace-files serve <socket-path>")
+	}
+	return server.ListenAndServe(args[0], server.New())
+}
+
+// rpcTraceCmd implements `trace-files --rpc <socket-path> program [arg]...`:
+// it reproduces trace-files' ordinary output, but by driving a session on
+// a server already running `trace-files serve`, rather than tracing the
+// program itself.
+func rpcTraceCmd(socketPath string, argv []string) error {
+	client, err := server.Dial(socketPath)
+	if err != nil {
+		return fmt.Errorf("error connecting to %s: %w", socketPath, err)
+	}
+	defer client.Close()
+
+	sid, err := client.Run(argv, os.Environ())
+	if err != nil {
+		return fmt.Errorf("error starting trace: %w", err)
+	}
+
+	events, err := client.Events(sid)
+	if err != nil {
+		return fmt.Errorf("error subscribing to events: %w", err)
+	}
+
+	files := make(fileSet)
+	for e := range events {
+		if e.FileOpened == nil {
+			continue
+		}
+		var mode accessMode
+		switch e.FileOpened.Mode {
+		case "write":
+			mode = accessWrite
+		case "exec":
+			mode = accessExec
+		default:
+			mode = accessRead
+		}
+		files[e.FileOpened.Path] = mode
+	}
+
+	return writeReport(os.Stdout, formatText, traceResult{Argv: argv, Files: files})
+}
@@ -0,0 +1,84 @@
+// +build linux
+
+package server
+
+import (
+	"net/rpc"
+	"time"
+)
+
+// Client is a thin wrapper around net/rpc.Client for talking to a Server.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to a Server listening on the given Unix socket.
+func Dial(socketPath string) (*Client, error) {
+	c, err := rpc.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{rpc: c}, nil
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// Run asks the server to start and trace argv, with the given environment
+// (or the server's own environment, if env is nil).
+func (c *Client) Run(argv, env []string) (SessionID, error) {
+	var reply SessionReply
+	err := c.rpc.Call("Server.Run", &RunArgs{Argv: argv, Env: env}, &reply)
+	return reply.Session, err
+}
+
+// Attach asks the server to start tracing an already-running process.
+func (c *Client) Attach(pid int) (SessionID, error) {
+	var reply SessionReply
+	err := c.rpc.Call("Server.Attach", &AttachArgs{PID: pid}, &reply)
+	return reply.Session, err
+}
+
+// Detach tells the server to forget about sid. It does not kill the
+// traced process.
+func (c *Client) Detach(sid SessionID) error {
+	return c.rpc.Call("Server.Detach", &DetachArgs{Session: sid}, &struct{}{})
+}
+
+// ListFiles returns every file observed so far in sid.
+func (c *Client) ListFiles(sid SessionID) ([]FileOpened, error) {
+	var reply ListFilesReply
+	err := c.rpc.Call("Server.ListFiles", &ListFilesArgs{Session: sid}, &reply)
+	return reply.Files, err
+}
+
+// pollInterval is how often Events polls the server for new events.
+const pollInterval = 50 * time.Millisecond
+
+// Events streams sid's events as they're observed, by polling the server
+// in the background. The channel is closed once the session's process
+// tree has exited and every event has been delivered.
+func (c *Client) Events(sid SessionID) (<-chan Event, error) {
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+		after := 0
+		for {
+			var reply PollReply
+			if err := c.rpc.Call("Server.Poll", &PollArgs{Session: sid, After: after}, &reply); err != nil {
+				return
+			}
+			for _, e := range reply.Events {
+				ch <- e
+			}
+			after = reply.Next
+			if reply.Done {
+				return
+			}
+			time.Sleep(pollInterval)
+		}
+	}()
+	return ch, nil
+}
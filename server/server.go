@@ -0,0 +1,290 @@
+// Package server exposes trace-files' ptrace loop behind an RPC service
+// over a Unix socket, in the spirit of Go's ogle debugger server, so
+// long-running builds can be traced from a separate process: an editor, a
+// CI orchestrator, or anything else that wants live file-access telemetry.
+//
+// ptrace requires that the thread which attached to a tracee be the one
+// that issues every further ptrace call for it. Server therefore runs each
+// session's ptracer.Loop on its own goroutine locked to its OS thread
+// (runtime.LockOSThread) and only ever touches a session's state through
+// its mutex-guarded event log, never by calling back into the tracing
+// goroutine.
+
+// +build linux
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"trace-files/ptracer"
+)
+
+// session holds the state for one traced process tree: the growing log of
+// events Poll hands out, and the files seen so far for ListFiles.
+type session struct {
+	mu     sync.Mutex
+	events []Event
+	files  map[string]FileOpened
+	done   bool
+}
+
+func newSession() *session {
+	return &session{files: make(map[string]FileOpened)}
+}
+
+func (s *session) append(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	if e.Kind == EventFileOpened {
+		s.files[e.FileOpened.Path] = *e.FileOpened
+	}
+}
+
+// markDone records that every tracee in the session's process tree has
+// exited, so Poll's Done flag (and Client.Events) can stop waiting for
+// more events.
+func (s *session) markDone() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = true
+}
+
+func (s *session) poll(after int) PollReply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []Event
+	if after < len(s.events) {
+		events = append(events, s.events[after:]...)
+	}
+	return PollReply{Events: events, Next: len(s.events), Done: s.done}
+}
+
+func (s *session) listFiles() []FileOpened {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := make([]FileOpened, 0, len(s.files))
+	for _, f := range s.files {
+		files = append(files, f)
+	}
+	return files
+}
+
+// Server is the RPC service. Its methods are exported for net/rpc and are
+// not meant to be called directly - use Client instead.
+type Server struct {
+	mu       sync.Mutex
+	sessions map[SessionID]*session
+	nextID   int
+}
+
+// New creates an empty Server.
+func New() *Server {
+	return &Server{sessions: make(map[SessionID]*session)}
+}
+
+func (s *Server) addSession() (SessionID, *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	sid := SessionID(fmt.Sprintf("sess-%d", s.nextID))
+	sess := newSession()
+	s.sessions[sid] = sess
+	return sid, sess
+}
+
+func (s *Server) session(sid SessionID) (*session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[sid]
+	if !ok {
+		return nil, fmt.Errorf("unknown session %q", sid)
+	}
+	return sess, nil
+}
+
+// Run starts args.Argv[0] with args.Argv[1:], traces it, and returns a
+// SessionID whose events can be read with Poll.
+func (s *Server) Run(args *RunArgs, reply *SessionReply) error {
+	if len(args.Argv) == 0 {
+		return fmt.Errorf("empty argv")
+	}
+
+	cmd := exec.Command(args.Argv[0], args.Argv[1:]...)
+	if len(args.Env) > 0 {
+		cmd.Env = args.Env
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &unix.SysProcAttr{Ptrace: true}
+
+	sid, sess := s.addSession()
+
+	started := make(chan error, 1)
+	go s.trace(cmd, sess, started)
+
+	if err := <-started; err != nil {
+		return err
+	}
+	reply.Session = sid
+	return nil
+}
+
+// Attach starts tracing an already-running process.
+func (s *Server) Attach(args *AttachArgs, reply *SessionReply) error {
+	sid, sess := s.addSession()
+
+	started := make(chan error, 1)
+	go s.traceAttached(args.PID, sess, started)
+
+	if err := <-started; err != nil {
+		return err
+	}
+	reply.Session = sid
+	return nil
+}
+
+// trace runs on its own locked OS thread: it starts cmd under ptrace, then
+// hands the traced pid to ptracer.Loop for the rest of the session.
+func (s *Server) trace(cmd *exec.Cmd, sess *session, started chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := cmd.Start(); err != nil {
+		started <- fmt.Errorf("error starting command: %w", err)
+		return
+	}
+	if err := cmd.Wait(); err != nil {
+		// We expect "trace/breakpoint trap" here, from the exec-induced stop.
+	}
+	pid := cmd.Process.Pid
+	if err := unix.PtraceSetOptions(pid, ptracer.Options); err != nil {
+		started <- fmt.Errorf("error setting ptrace options: %w", err)
+		return
+	}
+	started <- nil
+
+	s.runLoop(pid, sess)
+}
+
+// traceAttached runs on its own locked OS thread, attaches to pid, and
+// hands it to ptracer.Loop for the rest of the session.
+func (s *Server) traceAttached(pid int, sess *session, started chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := unix.PtraceAttach(pid); err != nil {
+		started <- fmt.Errorf("error attaching to pid %d: %w", pid, err)
+		return
+	}
+	var status unix.WaitStatus
+	if _, err := unix.Wait4(pid, &status, 0, nil); err != nil {
+		started <- fmt.Errorf("error waiting for pid %d: %w", pid, err)
+		return
+	}
+	if err := unix.PtraceSetOptions(pid, ptracer.Options); err != nil {
+		started <- fmt.Errorf("error setting ptrace options: %w", err)
+		return
+	}
+	started <- nil
+
+	s.runLoop(pid, sess)
+}
+
+func (s *Server) runLoop(pid int, sess *session) {
+	// active tracks how many tracees in the tree are still running, so
+	// the session is only marked done once the whole tree has exited -
+	// not at the first child or thread to do so. It starts at 1 for the
+	// root pid and is kept in step with ptracer.Loop's own tracees map by
+	// mirroring every OnFork/OnExit it reports.
+	active := 1
+	hooks := ptracer.Hooks{
+		OnFileAccess: func(tid int, fa ptracer.FileAccess) {
+			sess.append(Event{Kind: EventFileOpened, FileOpened: &FileOpened{
+				Path:      fa.Path,
+				Mode:      fa.Mode.String(),
+				PID:       pid,
+				TID:       tid,
+				Timestamp: time.Now(),
+			}})
+		},
+		OnFork: func(parent, child int) {
+			active++
+			sess.append(Event{Kind: EventProcessForked, ProcessForked: &ProcessForked{Parent: parent, Child: child}})
+		},
+		OnExit: func(tid int, code int) {
+			sess.append(Event{Kind: EventProcessExited, ProcessExited: &ProcessExited{PID: tid, Code: code}})
+			active--
+			if active == 0 {
+				sess.markDone()
+			}
+		},
+	}
+	// A loop error (e.g. the last tracee already gone) just ends the
+	// session; whatever was captured before the error is still valid.
+	_ = ptracer.Loop(pid, hooks)
+	sess.markDone()
+}
+
+// Poll returns every event recorded since args.After.
+func (s *Server) Poll(args *PollArgs, reply *PollReply) error {
+	sess, err := s.session(args.Session)
+	if err != nil {
+		return err
+	}
+	*reply = sess.poll(args.After)
+	return nil
+}
+
+// ListFiles returns every file observed so far in a session.
+func (s *Server) ListFiles(args *ListFilesArgs, reply *ListFilesReply) error {
+	sess, err := s.session(args.Session)
+	if err != nil {
+		return err
+	}
+	reply.Files = sess.listFiles()
+	return nil
+}
+
+// Detach forgets a session. It does not kill the traced process.
+func (s *Server) Detach(args *DetachArgs, reply *struct{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, args.Session)
+	return nil
+}
+
+// ListenAndServe registers s on a new net/rpc server and serves it over a
+// Unix socket at socketPath until the listener is closed.
+func ListenAndServe(socketPath string, s *Server) error {
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("Server", s); err != nil {
+		return fmt.Errorf("error registering RPC service: %w", err)
+	}
+
+	_ = os.Remove(socketPath) // ignore error: socketPath may simply not exist yet
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", socketPath, err)
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("error accepting connection: %w", err)
+		}
+		go rpcServer.ServeConn(conn)
+	}
+}
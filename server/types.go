@@ -0,0 +1,96 @@
+// +build linux
+
+package server
+
+import "time"
+
+// SessionID identifies one traced process tree.
+type SessionID string
+
+// EventKind distinguishes the variants of Event.
+type EventKind int
+
+const (
+	EventFileOpened EventKind = iota
+	EventProcessForked
+	EventProcessExited
+)
+
+// Event is one occurrence streamed from a session via Client.Events. Only
+// the field matching Kind is populated.
+type Event struct {
+	Kind          EventKind
+	FileOpened    *FileOpened    `json:",omitempty"`
+	ProcessForked *ProcessForked `json:",omitempty"`
+	ProcessExited *ProcessExited `json:",omitempty"`
+}
+
+// FileOpened reports a single file access observed in a session.
+type FileOpened struct {
+	Path      string
+	Mode      string // "read", "write", or "exec"
+	PID       int
+	TID       int
+	Timestamp time.Time
+}
+
+// ProcessForked reports that a tracee forked, vforked, or cloned.
+type ProcessForked struct {
+	Parent int
+	Child  int
+}
+
+// ProcessExited reports that a tracee exited.
+type ProcessExited struct {
+	PID  int
+	Code int
+}
+
+// RunArgs are the arguments to Server.Run.
+type RunArgs struct {
+	Argv []string
+	Env  []string
+}
+
+// AttachArgs are the arguments to Server.Attach.
+type AttachArgs struct {
+	PID int
+}
+
+// SessionReply is returned by Run and Attach.
+type SessionReply struct {
+	Session SessionID
+}
+
+// PollArgs are the arguments to Server.Poll.
+type PollArgs struct {
+	Session SessionID
+	// After is the index of the last event the caller has already seen;
+	// Poll returns everything after it.
+	After int
+}
+
+// PollReply is returned by Server.Poll.
+type PollReply struct {
+	Events []Event
+	// Next is the After value the caller should pass on its next Poll.
+	Next int
+	// Done is true once the session's process tree has exited and no
+	// further events will arrive.
+	Done bool
+}
+
+// DetachArgs are the arguments to Server.Detach.
+type DetachArgs struct {
+	Session SessionID
+}
+
+// ListFilesArgs are the arguments to Server.ListFiles.
+type ListFilesArgs struct {
+	Session SessionID
+}
+
+// ListFilesReply is returned by Server.ListFiles.
+type ListFilesReply struct {
+	Files []FileOpened
+}
@@ -0,0 +1,166 @@
+// Package syscalls provides architecture-independent lookup tables
+// describing, for a given GOARCH and syscall number, whether the tracer
+// knows how to pull a file path out of that syscall's arguments and what
+// to do with it once it has one.
+//
+// The numbers in each table come from the corresponding
+// zsysnum_linux_<arch>.go in golang.org/x/sys/unix.
+package syscalls
+
+// Mode classifies how a syscall uses the path it names.
+type Mode int
+
+const (
+	ModeRead Mode = iota
+	ModeWrite
+	ModeExec
+)
+
+// ArgKind describes what a syscall argument register holds, so a caller
+// can find the pieces it needs without switching on the syscall name.
+type ArgKind int
+
+const (
+	ArgNone ArgKind = iota
+	ArgPath
+	ArgDirFD
+	ArgFlags
+)
+
+// Info describes one syscall the tracer knows how to extract a path from.
+type Info struct {
+	Name string
+	Mode Mode
+	Args [6]ArgKind
+}
+
+// PathArg returns the argument index holding the path string, or -1 if
+// this syscall has none.
+func (i Info) PathArg() int {
+	return i.argIndex(ArgPath)
+}
+
+// DirFDArg returns the argument index holding the directory fd that a
+// relative path is resolved against (the "*at" family), or -1 if this
+// syscall takes no dirfd, i.e. paths are always resolved against cwd.
+func (i Info) DirFDArg() int {
+	return i.argIndex(ArgDirFD)
+}
+
+// FlagsArg returns the argument index holding an open(2)-style flags
+// word, or -1 if this syscall has none.
+func (i Info) FlagsArg() int {
+	return i.argIndex(ArgFlags)
+}
+
+func (i Info) argIndex(kind ArgKind) int {
+	for idx, k := range i.Args {
+		if k == kind {
+			return idx
+		}
+	}
+	return -1
+}
+
+// Lookup returns the Info for syscall number nr on the given GOARCH value,
+// and false if nr is not one of the path-bearing syscalls this package
+// knows about (or arch is not supported).
+func Lookup(arch string, nr uint64) (Info, bool) {
+	table, ok := tables[arch]
+	if !ok {
+		return Info{}, false
+	}
+	info, ok := table[nr]
+	return info, ok
+}
+
+var tables = map[string]map[uint64]Info{
+	"amd64": amd64Table,
+	"arm64": arm64Table,
+	"386":   x86Table,
+}
+
+// fchdirNrs holds fchdir's syscall number per GOARCH. fchdir takes only a
+// file descriptor - no path argument at all - so it doesn't fit the
+// ArgPath/ArgDirFD shape Info describes and isn't in the tables above; a
+// caller that wants to follow cwd changes needs to check for it
+// separately with FchdirNr.
+var fchdirNrs = map[string]uint64{
+	"amd64": 81,
+	"arm64": 50,
+	"386":   133,
+}
+
+// FchdirNr returns fchdir's syscall number on the given GOARCH value, and
+// false if arch is not supported.
+func FchdirNr(arch string) (uint64, bool) {
+	nr, ok := fchdirNrs[arch]
+	return nr, ok
+}
+
+// amd64Table holds the x86_64 syscall numbers. amd64 is the only
+// architecture with both the plain (open, stat, ...) and "*at" forms of
+// each syscall.
+var amd64Table = map[uint64]Info{
+	2:   {Name: "open", Mode: ModeRead, Args: [6]ArgKind{ArgPath, ArgFlags}},
+	4:   {Name: "stat", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	6:   {Name: "lstat", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	21:  {Name: "access", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	59:  {Name: "execve", Mode: ModeExec, Args: [6]ArgKind{ArgPath}},
+	80:  {Name: "chdir", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	82:  {Name: "rename", Mode: ModeWrite, Args: [6]ArgKind{ArgPath}},
+	83:  {Name: "mkdir", Mode: ModeWrite, Args: [6]ArgKind{ArgPath}},
+	87:  {Name: "unlink", Mode: ModeWrite, Args: [6]ArgKind{ArgPath}},
+	89:  {Name: "readlink", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	257: {Name: "openat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath, ArgFlags}},
+	258: {Name: "mkdirat", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	262: {Name: "newfstatat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	263: {Name: "unlinkat", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	264: {Name: "renameat", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	267: {Name: "readlinkat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	269: {Name: "faccessat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	316: {Name: "renameat2", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	322: {Name: "execveat", Mode: ModeExec, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	437: {Name: "openat2", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+}
+
+// arm64Table holds the aarch64 syscall numbers. arm64 only ever had the
+// "*at" family - there is no bare open/stat/access/unlink/... - so every
+// path syscall here takes a dirfd.
+var arm64Table = map[uint64]Info{
+	34:  {Name: "mkdirat", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	35:  {Name: "unlinkat", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	38:  {Name: "renameat", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	48:  {Name: "faccessat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	49:  {Name: "chdir", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	56:  {Name: "openat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath, ArgFlags}},
+	78:  {Name: "readlinkat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	79:  {Name: "newfstatat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	221: {Name: "execve", Mode: ModeExec, Args: [6]ArgKind{ArgPath}},
+	276: {Name: "renameat2", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	281: {Name: "execveat", Mode: ModeExec, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	437: {Name: "openat2", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+}
+
+// x86Table holds the ia32 (386) syscall numbers.
+var x86Table = map[uint64]Info{
+	5:   {Name: "open", Mode: ModeRead, Args: [6]ArgKind{ArgPath, ArgFlags}},
+	10:  {Name: "unlink", Mode: ModeWrite, Args: [6]ArgKind{ArgPath}},
+	11:  {Name: "execve", Mode: ModeExec, Args: [6]ArgKind{ArgPath}},
+	12:  {Name: "chdir", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	33:  {Name: "access", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	38:  {Name: "rename", Mode: ModeWrite, Args: [6]ArgKind{ArgPath}},
+	39:  {Name: "mkdir", Mode: ModeWrite, Args: [6]ArgKind{ArgPath}},
+	85:  {Name: "readlink", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	106: {Name: "stat", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	107: {Name: "lstat", Mode: ModeRead, Args: [6]ArgKind{ArgPath}},
+	295: {Name: "openat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath, ArgFlags}},
+	296: {Name: "mkdirat", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	300: {Name: "newfstatat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	301: {Name: "unlinkat", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	302: {Name: "renameat", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	305: {Name: "readlinkat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	307: {Name: "faccessat", Mode: ModeRead, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	353: {Name: "renameat2", Mode: ModeWrite, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+	358: {Name: "execveat", Mode: ModeExec, Args: [6]ArgKind{ArgDirFD, ArgPath}},
+}
@@ -0,0 +1,88 @@
+package syscalls
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		arch string
+		nr   uint64
+		want string
+	}{
+		{name: "amd64 openat", arch: "amd64", nr: 257, want: "openat"},
+		{name: "amd64 execve", arch: "amd64", nr: 59, want: "execve"},
+		{name: "arm64 openat", arch: "arm64", nr: 56, want: "openat"},
+		{name: "arm64 execve", arch: "arm64", nr: 221, want: "execve"},
+		{name: "386 open", arch: "386", nr: 5, want: "open"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := Lookup(tt.arch, tt.nr)
+			if !ok {
+				t.Fatalf("Lookup(%q, %d): not found", tt.arch, tt.nr)
+			}
+			if info.Name != tt.want {
+				t.Errorf("Lookup(%q, %d).Name = %q, want %q", tt.arch, tt.nr, info.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	if _, ok := Lookup("amd64", 999999); ok {
+		t.Error("Lookup: expected no match for an unused amd64 syscall number")
+	}
+	if _, ok := Lookup("riscv64", 0); ok {
+		t.Error("Lookup: expected no match for an unsupported arch")
+	}
+}
+
+func TestInfoPathArgAndDirFDArg(t *testing.T) {
+	openat, ok := Lookup("amd64", 257)
+	if !ok {
+		t.Fatal("Lookup(amd64, 257): not found")
+	}
+	if got := openat.DirFDArg(); got != 0 {
+		t.Errorf("openat.DirFDArg() = %d, want 0", got)
+	}
+	if got := openat.PathArg(); got != 1 {
+		t.Errorf("openat.PathArg() = %d, want 1", got)
+	}
+
+	open, ok := Lookup("amd64", 2)
+	if !ok {
+		t.Fatal("Lookup(amd64, 2): not found")
+	}
+	if got := open.DirFDArg(); got != -1 {
+		t.Errorf("open.DirFDArg() = %d, want -1 (no dirfd)", got)
+	}
+	if got := open.PathArg(); got != 0 {
+		t.Errorf("open.PathArg() = %d, want 0", got)
+	}
+}
+
+func TestFchdirNr(t *testing.T) {
+	tests := []struct {
+		arch   string
+		want   uint64
+		wantOK bool
+	}{
+		{arch: "amd64", want: 81, wantOK: true},
+		{arch: "arm64", want: 50, wantOK: true},
+		{arch: "386", want: 133, wantOK: true},
+		{arch: "riscv64", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arch, func(t *testing.T) {
+			nr, ok := FchdirNr(tt.arch)
+			if ok != tt.wantOK {
+				t.Fatalf("FchdirNr(%q) ok = %v, want %v", tt.arch, ok, tt.wantOK)
+			}
+			if ok && nr != tt.want {
+				t.Errorf("FchdirNr(%q) = %d, want %d", tt.arch, nr, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// +build linux
+
+package main
+
+import (
+	"trace-files/ptracer"
+)
+
+// accessMode is an alias for ptracer.AccessMode, kept so the rest of
+// package main (report.go, pack.go) doesn't need to import ptracer just to
+// spell out a type name.
+type accessMode = ptracer.AccessMode
+
+const (
+	accessRead   = ptracer.AccessRead
+	accessWrite  = ptracer.AccessWrite
+	accessExec   = ptracer.AccessExec
+	accessElfDep = ptracer.AccessElfDep
+)
+
+// fileSet collects every path the tracer has observed, along with the most
+// significant access mode seen for it (exec > write > read), so reports can
+// say more than just "this path was touched".
+type fileSet map[string]accessMode
+
+func (fs fileSet) add(fa ptracer.FileAccess) {
+	if existing, ok := fs[fa.Path]; !ok || modeRank(fa.Mode) > modeRank(existing) {
+		fs[fa.Path] = fa.Mode
+	}
+}
+
+// modeRank orders access modes by how much they tell us about a path:
+// an observed exec/write/read always wins over accessElfDep, which is
+// just an inference from another file's dependencies, not something we
+// actually saw the tracee do.
+func modeRank(m accessMode) int {
+	switch m {
+	case accessExec:
+		return 3
+	case accessWrite:
+		return 2
+	case accessRead:
+		return 1
+	default: // accessElfDep
+		return 0
+	}
+}
+
+// runTracer drives the ptrace event loop for the process tree rooted at
+// pid, which must already be stopped at its initial exec trap with
+// ptracer.Options set, and returns every file path observed during the
+// trace.
+func runTracer(pid int) (fileSet, error) {
+	filesCaught := make(fileSet)
+	hooks := ptracer.Hooks{
+		OnFileAccess: func(tid int, fa ptracer.FileAccess) {
+			filesCaught.add(fa)
+		},
+	}
+	if err := ptracer.Loop(pid, hooks); err != nil {
+		return nil, err
+	}
+	return filesCaught, nil
+}